@@ -0,0 +1,28 @@
+package freelist_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/weiwenchen2022/freelist"
+)
+
+// freeBuffers pools *bytes.Buffer directly. Unlike the intrusive FreeList,
+// FreeListOf needs no "next" field on bytes.Buffer itself.
+var freeBuffers = freelist.FreeListOf[bytes.Buffer]{
+	New: func() *bytes.Buffer {
+		return new(bytes.Buffer)
+	},
+	Reset: (*bytes.Buffer).Reset,
+}
+
+func Example_of() {
+	b := freeBuffers.Get()
+	defer freeBuffers.Put(b)
+
+	b.WriteString("hello, ")
+	b.WriteString("freelist")
+	fmt.Println(b.String())
+
+	// Output: hello, freelist
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
@@ -34,6 +35,21 @@ import (
 // that scenario. It is more efficient to have such objects implement their own
 // free list.
 //
+// Internally, a FreeList shards its storage across one list per P (the
+// current goroutine is pinned to its P with runtime_procPin while it touches
+// that list, the same primitive sync.Pool relies on), and only falls back to
+// a single mutex-protected central list when a P's own items have run out.
+// This turns the single global lock of a naive free list into GOMAXPROCS
+// effectively-independent locks that are almost always uncontended, since in
+// steady state only the P's own goroutines ever touch its list. A victim
+// copy of the per-P lists is kept across one extra garbage collection cycle
+// before being dropped, mirroring sync.Pool's two-generation behavior so
+// that a list does not get wiped out by every GC.
+//
+// By default a FreeList grows without bound; set MaxSize to cap the number
+// of items it retains, in which case Put drops an item per EvictionPolicy
+// (calling OnEvict, if set) whenever that would push the list over the cap.
+//
 // A FreeList must not be copied after first use.
 //
 // In the terminology of the Go memory model, a call to Put(x) “synchronizes before”
@@ -43,8 +59,7 @@ import (
 type FreeList[E any] struct {
 	noCopy noCopy
 
-	mu   sync.Mutex // protects free
-	free *E
+	core shardedCore[*E]
 
 	initOnce sync.Once
 	next     uintptr
@@ -58,6 +73,107 @@ type FreeList[E any] struct {
 	// a value when Get would return a cached value.
 	// It may not be changed concurrently with calls to Get.
 	Reset func(*E)
+
+	// MaxSize caps the number of items the FreeList retains across its
+	// local, victim, and central storage combined. Zero, the default,
+	// leaves the list unbounded, the original behavior. It may not be
+	// changed concurrently with calls to Put.
+	MaxSize int
+
+	// EvictionPolicy selects which item Put drops once MaxSize is
+	// reached. It may not be changed concurrently with calls to Put.
+	EvictionPolicy EvictionPolicy
+
+	// OnEvict, if non-nil, is called with each item Put drops to bring
+	// the list back under MaxSize.
+	OnEvict func(*E)
+
+	// Observer, if non-nil, is called once per Get hit, Get miss, and
+	// eviction. It may not be changed concurrently with calls to Get or
+	// Put.
+	Observer func(StatsEvent)
+
+	size      atomic.Int64
+	gets      atomic.Uint64
+	puts      atomic.Uint64
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// StatsEvent identifies what Observer is being notified about.
+type StatsEvent int
+
+const (
+	// EventHit fires when Get is satisfied from l's own storage.
+	EventHit StatsEvent = iota
+	// EventMiss fires when Get falls through to New (or, with no New,
+	// returns nil).
+	EventMiss
+	// EventEvict fires when Put drops an item to bring the list back
+	// under MaxSize.
+	EventEvict
+)
+
+// Stats is a point-in-time snapshot of a FreeList's cumulative counters.
+type Stats struct {
+	Gets      uint64
+	Puts      uint64
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int64
+}
+
+// Stats returns l's current counters. The counters are maintained with
+// atomic.Uint64, so reading them never contends with concurrent Get or Put
+// calls the way locking the main mutex to collect stats would.
+func (l *FreeList[E]) Stats() Stats {
+	return Stats{
+		Gets:      l.gets.Load(),
+		Puts:      l.puts.Load(),
+		Hits:      l.hits.Load(),
+		Misses:    l.misses.Load(),
+		Evictions: l.evictions.Load(),
+		Size:      l.size.Load(),
+	}
+}
+
+// EvictionPolicy selects which item a bounded FreeList drops once MaxSize
+// is reached.
+type EvictionPolicy int
+
+const (
+	// EvictFIFO drops an item from the central overflow list, which by
+	// construction holds the items that have been sitting unused the
+	// longest. Only when the central list is empty does it fall back to
+	// the calling P's own shard, and there it drops the shard's head, the
+	// most recently Put item, just as EvictLIFO does; the oldest items of
+	// a shard are not searched for. This is the zero value and the
+	// default.
+	EvictFIFO EvictionPolicy = iota
+
+	// EvictLIFO drops the most recently Put item: in practice the item
+	// Put just added, which makes Put a no-op (aside from OnEvict) once
+	// the list is full.
+	EvictLIFO
+
+	// EvictRandom drops an item from a pseudo-randomly chosen shard
+	// (falling back to the central list if that shard is empty), giving
+	// an approximately, not exactly, uniform eviction distribution.
+	EvictRandom
+)
+
+// getNext and setNext read and write the intrusive "next" field located at
+// offset l.next within *E, discovered once by init via reflection. They are
+// handed to l.core as plain function values so the sharding/pinning/victim
+// logic in shardedCore never needs to know how a "next" link is stored.
+func (l *FreeList[E]) getNext(x *E) *E {
+	return *(**E)(unsafe.Pointer(uintptr(unsafe.Pointer(x)) + l.next))
+}
+
+func (l *FreeList[E]) setNext(x, v *E) {
+	*(**E)(unsafe.Pointer(uintptr(unsafe.Pointer(x)) + l.next)) = v
 }
 
 // Get selects a last put back item from the FreeList, removes it from the
@@ -67,34 +183,169 @@ type FreeList[E any] struct {
 // the result of calling l.New.
 func (l *FreeList[E]) Get() *E {
 	l.init()
+	l.gets.Add(1)
 
-	l.mu.Lock()
-	x := l.free
+	x := l.core.get()
 	if x != nil {
-		l.free = *(**E)(unsafe.Pointer(uintptr(unsafe.Pointer(x)) + l.next))
-		l.mu.Unlock()
-
+		l.size.Add(-1)
+		l.hits.Add(1)
+		if l.Observer != nil {
+			l.Observer(EventHit)
+		}
 		if l.Reset != nil {
 			l.Reset(x)
 		}
-	} else {
-		l.mu.Unlock()
+		return x
+	}
 
-		if l.New != nil {
-			x = l.New()
-		}
+	l.misses.Add(1)
+	if l.Observer != nil {
+		l.Observer(EventMiss)
+	}
+	if l.New != nil {
+		x = l.New()
 	}
 	return x
 }
 
+// Len reports the number of items currently retained by l, across its
+// local, victim, and central storage.
+func (l *FreeList[E]) Len() int {
+	return int(l.size.Load())
+}
+
 // Put adds x to the free list.
 func (l *FreeList[E]) Put(x *E) {
+	if x == nil {
+		return
+	}
+	l.init()
+	l.puts.Add(1)
+
+	pid := l.core.put(x)
+
+	if n := l.size.Add(1); l.MaxSize > 0 && n > int64(l.MaxSize) {
+		if evicted := l.evictOne(pid); evicted != nil {
+			l.size.Add(-1)
+			l.evictions.Add(1)
+			if l.Observer != nil {
+				l.Observer(EventEvict)
+			}
+			if l.OnEvict != nil {
+				l.OnEvict(evicted)
+			}
+		}
+	}
+}
+
+// evictOne drops and returns one item per l.EvictionPolicy, or nil if the
+// list turned out to have nothing left to evict.
+func (l *FreeList[E]) evictOne(pid int) *E {
+	switch l.EvictionPolicy {
+	case EvictLIFO:
+		return l.core.evictFromShard(pid)
+	case EvictRandom:
+		return l.core.evictRandom()
+	default: // EvictFIFO
+		if x := l.core.getCentral(); x != nil {
+			return x
+		}
+		return l.core.evictFromShard(pid)
+	}
+}
+
+// GetN fills dst[:n] with items taken from the free list, where n is the
+// returned count, calling l.New for any it could not satisfy from cache
+// (n < len(dst) with l.New nil otherwise). Unlike calling Get len(dst)
+// times, the local, victim, and central lists are each only locked once
+// for the whole batch, which amortizes synchronization across a burst of
+// acquisitions the way a connection reading many same-sized messages at
+// once would want.
+func (l *FreeList[E]) GetN(dst []*E) int {
+	if len(dst) == 0 {
+		return 0
+	}
 	l.init()
+	l.gets.Add(uint64(len(dst)))
+
+	i := l.core.getN(dst)
+
+	hits, misses := i, len(dst)-i
+	if hits > 0 {
+		l.size.Add(-int64(hits))
+		l.hits.Add(uint64(hits))
+		if l.Reset != nil {
+			for _, x := range dst[:hits] {
+				l.Reset(x)
+			}
+		}
+	}
+	if misses > 0 {
+		l.misses.Add(uint64(misses))
+	}
+	if l.Observer != nil {
+		for j := 0; j < hits; j++ {
+			l.Observer(EventHit)
+		}
+		for j := 0; j < misses; j++ {
+			l.Observer(EventMiss)
+		}
+	}
 
-	l.mu.Lock()
-	*(**E)(unsafe.Pointer(uintptr(unsafe.Pointer(x)) + l.next)) = l.free
-	l.free = x
-	l.mu.Unlock()
+	if i < len(dst) && l.New != nil {
+		for ; i < len(dst); i++ {
+			dst[i] = l.New()
+		}
+	}
+	return i
+}
+
+// PutN adds all non-nil items of src to the free list, linking them into
+// the calling P's shard in O(len(src)) under a single pin and lock
+// acquisition, rather than locking once per element as len(src) calls to
+// Put would.
+func (l *FreeList[E]) PutN(src []*E) {
+	if len(src) == 0 {
+		return
+	}
+	l.init()
+
+	var head, tail *E
+	n := 0
+	for _, x := range src {
+		if x == nil {
+			continue
+		}
+		l.setNext(x, head)
+		head = x
+		if tail == nil {
+			tail = x
+		}
+		n++
+	}
+	if head == nil {
+		return
+	}
+	l.puts.Add(uint64(n))
+
+	pid := l.core.putN(head, tail, n)
+
+	if added := l.size.Add(int64(n)); l.MaxSize > 0 {
+		for added > int64(l.MaxSize) {
+			evicted := l.evictOne(pid)
+			if evicted == nil {
+				break
+			}
+			added = l.size.Add(-1)
+			l.evictions.Add(1)
+			if l.Observer != nil {
+				l.Observer(EventEvict)
+			}
+			if l.OnEvict != nil {
+				l.OnEvict(evicted)
+			}
+		}
+	}
 }
 
 // Dummy type used to generate an implicit panic. This must be defined at the
@@ -112,6 +363,9 @@ func (l *FreeList[E]) init() {
 			nextField, ok := typeOfE.FieldByName("next")
 			if ok && reflect.PointerTo(typeOfE) == nextField.Type {
 				l.next = nextField.Offset
+				l.core.getNext = l.getNext
+				l.core.setNext = l.setNext
+				l.core.onVictimDrop = func(n int64) { l.size.Add(-n) }
 				didPanic = false
 				return
 			}
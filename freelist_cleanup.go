@@ -0,0 +1,92 @@
+package freelist
+
+import (
+	"runtime"
+	"sync"
+)
+
+// cleanupTarget is implemented by shardedCore, the storage shared by
+// FreeList and FreeListTyped, so that each list's core can take part in the
+// shared victim-cache rotation below.
+type cleanupTarget interface {
+	// makeVictim turns whatever is currently the local generation into
+	// the new victim generation.
+	makeVictim()
+
+	// dropVictim drops whatever has survived a full GC cycle unused in
+	// the victim generation, accounting for anything it drops.
+	dropVictim()
+}
+
+// freeListCleanupMu guards the two generations of registered lists below.
+var freeListCleanupMu sync.Mutex
+
+// freeListAllLists holds every list that has been pinned since the last
+// cleanup; freeListOldLists is the previous such generation. Each cleanup
+// moves freeListAllLists to freeListOldLists unconditionally - regardless
+// of whether anything has pinned those lists again in the meantime - so a
+// list that was pinned even once is carried through exactly two cleanups
+// (one to turn its local generation into its victim generation, one to drop
+// that victim) the same way sync.Pool's allPools/oldPools carries a Pool
+// through two cleanups after its last use. Once both of those have run, the
+// list simply isn't in either slice any more and is never referenced again
+// by this package unless it gets pinned afresh.
+var freeListAllLists, freeListOldLists []cleanupTarget
+
+// freeListCleanupStarted guards against registering the package-wide
+// finalizer chain more than once per process.
+var freeListCleanupStarted sync.Once
+
+// freeListRegister adds l to the current generation, starting the
+// finalizer chain that drives cleanup if this is the first list ever
+// registered. It must be called with shards == nil, i.e. only when l has
+// no local generation of its own yet (first use, or just after its local
+// generation was made into a victim generation), mirroring how sync.Pool
+// re-adds itself to allPools.
+func freeListRegister(l cleanupTarget) {
+	freeListCleanupMu.Lock()
+	freeListAllLists = append(freeListAllLists, l)
+	freeListCleanupMu.Unlock()
+
+	freeListCleanupStarted.Do(freeListStartCleanup)
+}
+
+// freeListStartCleanup arms the one, package-wide cleanup sentinel. Its
+// finalizer drives every registered list's two-generation cleanup and then
+// re-arms a fresh sentinel for the next GC cycle. Critically, the sentinel
+// and the finalize function hold no reference to any particular list - only
+// the two generation slices above do, and those are what get handed off -
+// so lists are never kept alive by this machinery once callers stop using
+// them.
+func freeListStartCleanup() {
+	s := new(freeListCleanupSentinel)
+	runtime.SetFinalizer(s, freeListCleanupSentinelFinalize)
+}
+
+// freeListCleanupSentinel is given a size so that new returns a distinct
+// heap object: the runtime never runs a finalizer set on a zero-sized one.
+type freeListCleanupSentinel struct{ _ [16]byte }
+
+func freeListCleanupSentinelFinalize(s *freeListCleanupSentinel) {
+	freeListCleanupMu.Lock()
+	old, cur := freeListOldLists, freeListAllLists
+	freeListOldLists, freeListAllLists = cur, nil
+	freeListCleanupMu.Unlock()
+
+	// old is the generation that had its local turned into its victim at
+	// the previous cleanup; that victim has now sat unused through a full
+	// extra GC cycle, so it is dropped. This runs whether or not any of
+	// these lists were touched again in between.
+	for _, t := range old {
+		t.dropVictim()
+	}
+
+	// cur is every list pinned since the previous cleanup; whatever is in
+	// its local generation now becomes its victim generation, to be
+	// dropped at the cleanup after this one.
+	for _, t := range cur {
+		t.makeVictim()
+	}
+
+	freeListStartCleanup()
+}
@@ -0,0 +1,379 @@
+package freelist
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// freeListShardOverflow is the number of items a single P's shard may hold
+// before a push starts spilling the oldest arrivals into the central list,
+// so that other Ps have something to steal instead of always falling
+// through to New.
+const freeListShardOverflow = 256
+
+// freeListShardPad is a heuristic pad to push a shard's size up towards a
+// common cache line size (64-128 bytes on contemporary hardware), so that
+// two adjacent Ps' shards don't false-share.
+const freeListShardPad = 64
+
+//go:linkname runtime_procPin sync.runtime_procPin
+func runtime_procPin() int
+
+//go:linkname runtime_procUnpin sync.runtime_procUnpin
+func runtime_procUnpin()
+
+// freeListGrowMu serializes growth of any shardedCore's local shard slice.
+// It is only ever taken on the rare path where a P has never been seen
+// before by that core.
+var freeListGrowMu sync.Mutex
+
+// coreShard is one P's local free list for a shardedCore[P].
+type coreShard[P comparable] struct {
+	mu   sync.Mutex
+	head P
+	n    int
+
+	_ [freeListShardPad]byte
+}
+
+// shardedCore is the per-P sharded storage, victim cache, and central
+// overflow list shared by FreeList and FreeListTyped. P is whatever
+// pointer-shaped type the caller stores (*E for FreeList, PE for
+// FreeListTyped); getNext/setNext let the core read and write P's
+// intrusive "next" link without caring how that link is actually
+// implemented (an unsafe field offset for FreeList, interface methods for
+// FreeListTyped). Having one implementation of the sharding, pinning, and
+// GC-driven victim rotation means there is exactly one place to find, and
+// fix, a bug in that logic rather than one per list flavor.
+type shardedCore[P comparable] struct {
+	local  atomic.Pointer[[]coreShard[P]]
+	victim atomic.Pointer[[]coreShard[P]]
+
+	mu   sync.Mutex // protects free, the central overflow/steal target
+	free P
+
+	getNext func(P) P
+	setNext func(P, P)
+
+	// onVictimDrop, if non-nil, is called with the number of items found
+	// still sitting in the old victim generation when it is dropped
+	// during rotate, so a caller tracking its own total size (FreeList's
+	// Len/MaxSize accounting) can stay accurate.
+	onVictimDrop func(int64)
+}
+
+func (c *shardedCore[P]) pin() (*coreShard[P], int) {
+	pid := runtime_procPin()
+	shards := c.local.Load()
+	if shards != nil && pid < len(*shards) {
+		return &(*shards)[pid], pid
+	}
+	return c.pinSlow(pid)
+}
+
+func (c *shardedCore[P]) pinSlow(pid int) (*coreShard[P], int) {
+	runtime_procUnpin()
+
+	freeListGrowMu.Lock()
+	defer freeListGrowMu.Unlock()
+
+	pid = runtime_procPin()
+	shards := c.local.Load()
+	if shards != nil && pid < len(*shards) {
+		return &(*shards)[pid], pid
+	}
+
+	// shards == nil means c has no local generation of its own right
+	// now, either because this is its first use or because the last one
+	// was just made into a victim generation out from under it; either
+	// way it needs to (re-)join the set of cores the shared cleanup
+	// machinery tracks. See freeListAllLists's doc comment for why this
+	// does not leak c.
+	freeListRegister(c)
+
+	size := runtime.GOMAXPROCS(0)
+	grown := make([]coreShard[P], size)
+	c.local.Store(&grown)
+	return &grown[pid], pid
+}
+
+// makeVictim makes whatever is currently local the new victim generation,
+// discarding whatever was already there (dropVictim has already been called
+// on it, earlier in the same cleanup or in a previous one). It is called, unconditionally, on every core that
+// was pinned since the previous cleanup - see freeListCleanupSentinelFinalize.
+func (c *shardedCore[P]) makeVictim() {
+	c.victim.Store(c.local.Load())
+	c.local.Store(nil)
+}
+
+// dropVictim drops whatever is left in the victim generation: items that
+// were Put, made into the victim generation at the previous cleanup, and
+// never retrieved via Get since, so they have now survived a full extra GC
+// cycle unused. It reports how many items were dropped through
+// onVictimDrop, so a caller tracking its own total size (FreeList's
+// Len/MaxSize accounting) stays accurate. It is called, unconditionally, on
+// every core that was still tracked from the cleanup before last - see
+// freeListCleanupSentinelFinalize.
+func (c *shardedCore[P]) dropVictim() {
+	old := c.victim.Load()
+	if old == nil {
+		return
+	}
+	if n := coreShardsLen(old); n > 0 && c.onVictimDrop != nil {
+		c.onVictimDrop(n)
+	}
+	c.victim.Store(nil)
+}
+
+// coreShardsLen sums the items currently linked under each shard in
+// shards, locking each shard in turn so a concurrent Get/Put racing with a
+// rotation can't be double-counted or missed.
+func coreShardsLen[P comparable](shards *[]coreShard[P]) int64 {
+	var n int64
+	for i := range *shards {
+		shard := &(*shards)[i]
+		shard.mu.Lock()
+		n += int64(shard.n)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+// get pops a single item from c's local shard, falling back to the victim
+// and then the central list. The zero value of P means nothing was found.
+func (c *shardedCore[P]) get() P {
+	var zero P
+
+	shard, pid := c.pin()
+	shard.mu.Lock()
+	x := shard.head
+	if x != zero {
+		shard.head = c.getNext(x)
+		shard.n--
+	}
+	shard.mu.Unlock()
+	runtime_procUnpin()
+
+	if x == zero {
+		x = c.getVictim(pid)
+	}
+	if x == zero {
+		x = c.getCentral()
+	}
+	return x
+}
+
+// getVictim looks for an item left over from the previous GC cycle, first
+// in the shard for pid, then, since nothing writes to the victim cache
+// anymore, in every other victim shard in turn. Only once all of them are
+// empty does it give up on the victim generation entirely, so nothing is
+// discarded here that onVictimDrop would have had to account for.
+func (c *shardedCore[P]) getVictim(pid int) P {
+	var zero P
+
+	victims := c.victim.Load()
+	if victims == nil || len(*victims) == 0 {
+		return zero
+	}
+
+	size := len(*victims)
+	for i := 0; i < size; i++ {
+		if x := c.popShard(&(*victims)[(pid+i)%size]); x != zero {
+			return x
+		}
+	}
+
+	c.victim.CompareAndSwap(victims, nil)
+	return zero
+}
+
+func (c *shardedCore[P]) popShard(shard *coreShard[P]) P {
+	var zero P
+
+	shard.mu.Lock()
+	x := shard.head
+	if x != zero {
+		shard.head = c.getNext(x)
+		shard.n--
+	}
+	shard.mu.Unlock()
+	return x
+}
+
+func (c *shardedCore[P]) getCentral() P {
+	var zero P
+
+	c.mu.Lock()
+	x := c.free
+	if x != zero {
+		c.free = c.getNext(x)
+	}
+	c.mu.Unlock()
+	return x
+}
+
+// put pushes x onto c's local shard, spilling the shard's oldest arrival
+// into the central list if that would grow past freeListShardOverflow. It
+// returns the id of the P x was pushed onto, for callers (FreeList's
+// MaxSize enforcement) that want to evict from the same shard afterwards.
+func (c *shardedCore[P]) put(x P) int {
+	var zero P
+
+	shard, pid := c.pin()
+	shard.mu.Lock()
+	c.setNext(x, shard.head)
+	shard.head = x
+	shard.n++
+
+	var overflow P
+	if shard.n > freeListShardOverflow {
+		cur := shard.head
+		for k := 1; k < freeListShardOverflow; k++ {
+			cur = c.getNext(cur)
+		}
+		overflow = c.getNext(cur)
+		c.setNext(cur, zero)
+		shard.n--
+	}
+	shard.mu.Unlock()
+	runtime_procUnpin()
+
+	if overflow != zero {
+		c.mu.Lock()
+		c.setNext(overflow, c.free)
+		c.free = overflow
+		c.mu.Unlock()
+	}
+	return pid
+}
+
+// evictFromShard drops and returns the item at the head of pid's own
+// shard, or the zero value if it has nothing to give up.
+func (c *shardedCore[P]) evictFromShard(pid int) P {
+	var zero P
+	shards := c.local.Load()
+	if shards == nil || pid >= len(*shards) {
+		return zero
+	}
+	return c.popShard(&(*shards)[pid])
+}
+
+// evictRandom drops and returns an item from a pseudo-randomly chosen
+// shard, falling back to the central list if that shard is empty.
+func (c *shardedCore[P]) evictRandom() P {
+	var zero P
+	shards := c.local.Load()
+	if shards == nil || len(*shards) == 0 {
+		return c.getCentral()
+	}
+	if x := c.popShard(&(*shards)[rand.Intn(len(*shards))]); x != zero {
+		return x
+	}
+	return c.getCentral()
+}
+
+// getN fills dst with items taken from the local, victim, and central
+// lists, in that order, returning the number filled in. Each list is only
+// locked once for the whole batch, rather than once per element.
+func (c *shardedCore[P]) getN(dst []P) int {
+	shard, pid := c.pin()
+	i := c.popShardN(shard, dst, 0)
+	runtime_procUnpin()
+
+	if i < len(dst) {
+		i = c.getVictimN(dst, i, pid)
+	}
+	if i < len(dst) {
+		i = c.getCentralN(dst, i)
+	}
+	return i
+}
+
+// popShardN pops items from shard into dst[i:], stopping once dst is full
+// or shard is empty, under a single lock acquisition.
+func (c *shardedCore[P]) popShardN(shard *coreShard[P], dst []P, i int) int {
+	var zero P
+
+	shard.mu.Lock()
+	for i < len(dst) && shard.head != zero {
+		x := shard.head
+		shard.head = c.getNext(x)
+		shard.n--
+		dst[i] = x
+		i++
+	}
+	shard.mu.Unlock()
+	return i
+}
+
+func (c *shardedCore[P]) getVictimN(dst []P, i, pid int) int {
+	victims := c.victim.Load()
+	if victims == nil || len(*victims) == 0 {
+		return i
+	}
+	if pid >= len(*victims) {
+		pid = 0
+	}
+	i = c.popShardN(&(*victims)[pid], dst, i)
+	if i < len(dst) && pid != 0 {
+		i = c.popShardN(&(*victims)[0], dst, i)
+	}
+	return i
+}
+
+func (c *shardedCore[P]) getCentralN(dst []P, i int) int {
+	var zero P
+
+	c.mu.Lock()
+	for i < len(dst) && c.free != zero {
+		x := c.free
+		c.free = c.getNext(x)
+		dst[i] = x
+		i++
+	}
+	c.mu.Unlock()
+	return i
+}
+
+// putN links the already-chained list [head..tail] (n items, tail's next
+// unset) into c's local shard in O(n) under a single pin and lock
+// acquisition, spilling any excess over freeListShardOverflow into the
+// central list as a single spliced-in chain. It returns the id of the P
+// the chain was pushed onto.
+func (c *shardedCore[P]) putN(head, tail P, n int) int {
+	var zero P
+
+	shard, pid := c.pin()
+	shard.mu.Lock()
+	c.setNext(tail, shard.head)
+	shard.head = head
+	shard.n += n
+
+	var overflow P
+	if shard.n > freeListShardOverflow {
+		cur := shard.head
+		for k := 1; k < freeListShardOverflow; k++ {
+			cur = c.getNext(cur)
+		}
+		overflow = c.getNext(cur)
+		c.setNext(cur, zero)
+		shard.n = freeListShardOverflow
+	}
+	shard.mu.Unlock()
+	runtime_procUnpin()
+
+	if overflow != zero {
+		overflowTail := overflow
+		for c.getNext(overflowTail) != zero {
+			overflowTail = c.getNext(overflowTail)
+		}
+		c.mu.Lock()
+		c.setNext(overflowTail, c.free)
+		c.free = overflow
+		c.mu.Unlock()
+	}
+	return pid
+}
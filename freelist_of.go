@@ -0,0 +1,79 @@
+package freelist
+
+// node is the intrusive wrapper FreeListOf uses internally so that
+// arbitrary, third-party types can be pooled without having to add their
+// own "next" field. node itself satisfies the FreeList convention, so
+// FreeListOf is built by pooling *node[E] with a pair of ordinary
+// FreeList[node[E]] lists rather than reimplementing the per-P sharding.
+type node[E any] struct {
+	val  *E
+	next *node[E]
+}
+
+// A FreeListOf is a FreeList for types that cannot, or should not, be made
+// to embed an intrusive "next *T" field themselves - a pointer to a
+// third-party struct, for instance. It offers the same Get/New/Reset
+// behavior as FreeList, and shares its per-P sharded implementation, at the
+// cost of one small internal node allocation per item the first time that
+// item cycles through the list; the node is then cached and reused for
+// later items, so steady-state use allocates no more than FreeList does.
+//
+// A FreeListOf must not be copied after first use.
+type FreeListOf[E any] struct {
+	noCopy noCopy
+
+	// ready holds nodes currently wrapping a usable *E, ready for Get.
+	ready FreeList[node[E]]
+
+	// spares holds empty node shells left over once Get has unwrapped
+	// their value, so the next Put can reuse one instead of allocating.
+	spares FreeList[node[E]]
+
+	// New optionally specifies a function to generate
+	// a value when Get would otherwise return nil.
+	// It may not be changed concurrently with calls to Get.
+	New func() *E
+
+	// Reset optionally specifies a function to reset
+	// a value when Get would return a cached value.
+	// It may not be changed concurrently with calls to Get.
+	Reset func(*E)
+}
+
+// Get selects a last put back item from the FreeListOf, removes it, if
+// l.Reset is non-nil calling l.Reset with it, and returns it to the caller.
+//
+// If Get would otherwise return nil and l.New is non-nil, Get returns
+// the result of calling l.New.
+func (l *FreeListOf[E]) Get() *E {
+	n := l.ready.Get()
+	if n == nil {
+		if l.New != nil {
+			return l.New()
+		}
+		return nil
+	}
+
+	x := n.val
+	n.val = nil
+	l.spares.Put(n)
+
+	if l.Reset != nil {
+		l.Reset(x)
+	}
+	return x
+}
+
+// Put adds x to the free list.
+func (l *FreeListOf[E]) Put(x *E) {
+	if x == nil {
+		return
+	}
+
+	n := l.spares.Get()
+	if n == nil {
+		n = new(node[E])
+	}
+	n.val = x
+	l.ready.Put(n)
+}
@@ -0,0 +1,48 @@
+package freelist_test
+
+import (
+	"testing"
+
+	. "github.com/weiwenchen2022/freelist"
+)
+
+func TestFreeListOf(t *testing.T) {
+	type Point struct {
+		X, Y int
+	}
+
+	var l FreeListOf[Point]
+	if l.Get() != nil {
+		t.Fatal("expected empty")
+	}
+
+	l.Put(&Point{X: 1, Y: 1})
+	l.Put(&Point{X: 2, Y: 2})
+
+	if g := l.Get(); g.X != 2 {
+		t.Fatalf("got %d; want 2", g.X)
+	}
+	if g := l.Get(); g.X != 1 {
+		t.Fatalf("got %d; want 1", g.X)
+	}
+}
+
+func TestFreeListOfNew(t *testing.T) {
+	i := 0
+	l := FreeListOf[int]{
+		New: func() *int {
+			i++
+			return &i
+		},
+	}
+
+	if v := l.Get(); *v != 1 {
+		t.Errorf("got %d; want 1", *v)
+	}
+
+	n := 42
+	l.Put(&n)
+	if v := l.Get(); *v != 42 {
+		t.Errorf("got %d; want 42", *v)
+	}
+}
@@ -1,8 +1,11 @@
 package freelist_test
 
 import (
+	"reflect"
+	"runtime"
 	"sync"
 	"testing"
+	"time"
 
 	. "github.com/weiwenchen2022/freelist"
 )
@@ -143,6 +146,145 @@ func TestPanics(t *testing.T) {
 	}
 }
 
+func TestListMaxSize(t *testing.T) {
+	var evicted []int
+	l := FreeList[T]{
+		MaxSize: 2,
+		OnEvict: func(t *T) { evicted = append(evicted, t.A) },
+	}
+
+	l.Put(&T{A: 1})
+	l.Put(&T{A: 2})
+	if got := l.Len(); got != 2 {
+		t.Fatalf("Len() = %d; want 2", got)
+	}
+
+	l.Put(&T{A: 3})
+	if got := l.Len(); got != 2 {
+		t.Fatalf("Len() = %d; want 2 after eviction", got)
+	}
+	if len(evicted) != 1 {
+		t.Fatalf("got %d evictions; want 1", len(evicted))
+	}
+}
+
+func TestListGetNPutN(t *testing.T) {
+	var l FreeList[T]
+
+	src := make([]*T, 100)
+	for i := range src {
+		src[i] = &T{A: i}
+	}
+	l.PutN(src)
+	if got := l.Len(); got != len(src) {
+		t.Fatalf("Len() = %d; want %d", got, len(src))
+	}
+
+	dst := make([]*T, 100)
+	if got := l.GetN(dst); got != len(dst) {
+		t.Fatalf("GetN returned %d; want %d", got, len(dst))
+	}
+	if l.Len() != 0 {
+		t.Fatalf("Len() = %d; want 0", l.Len())
+	}
+
+	seen := make(map[int]bool, len(dst))
+	for _, x := range dst {
+		seen[x.A] = true
+	}
+	if len(seen) != len(src) {
+		t.Fatalf("GetN returned %d distinct items; want %d", len(seen), len(src))
+	}
+}
+
+func TestListStats(t *testing.T) {
+	var events []StatsEvent
+	l := FreeList[T]{
+		Observer: func(e StatsEvent) { events = append(events, e) },
+	}
+
+	l.Get() // miss, l.New is nil
+	l.Put(&T{A: 1})
+	l.Get() // hit
+
+	stats := l.Stats()
+	if stats.Gets != 2 {
+		t.Errorf("Gets = %d; want 2", stats.Gets)
+	}
+	if stats.Puts != 1 {
+		t.Errorf("Puts = %d; want 1", stats.Puts)
+	}
+	if stats.Hits != 1 {
+		t.Errorf("Hits = %d; want 1", stats.Hits)
+	}
+	if stats.Misses != 1 {
+		t.Errorf("Misses = %d; want 1", stats.Misses)
+	}
+
+	if want := []StatsEvent{EventMiss, EventHit}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v; want %v", events, want)
+	}
+}
+
+func TestListConcurrent(t *testing.T) {
+	const goroutines = 32
+	const perGoroutine = 1000
+
+	var l FreeList[T]
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				x := l.Get()
+				if x == nil {
+					x = &T{}
+				}
+				l.Put(x)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Drain whatever is left and make sure nothing panics or hangs;
+	// the sharded local lists, the victim cache, and the central
+	// overflow list must all still be walkable.
+	for l.Get() != nil {
+	}
+}
+
+func TestListGCDropsUnusedItems(t *testing.T) {
+	var l FreeList[T]
+
+	l.Put(&T{A: 1})
+	l.Put(&T{A: 2})
+	if got := l.Len(); got != 2 {
+		t.Fatalf("Len() = %d after Put; want 2", got)
+	}
+
+	// Nothing consumes the items, so two GC cycles - one to move them
+	// into the victim generation, one to drop that victim generation -
+	// should bring Len() back to 0, the same way an sync.Pool's items
+	// don't survive being unused across two cycles.
+	var got int
+	for i := 0; i < 30; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+		if got = l.Len(); got == 0 {
+			break
+		}
+	}
+	if got != 0 {
+		t.Fatalf("Len() = %d after GC cycles; want 0", got)
+	}
+
+	if l.Get() != nil {
+		t.Fatal("expected empty after GC dropped the unused items")
+	}
+}
+
 // nativeFreeList is a baseline implementation to the FreeList.
 type nativeFreeList struct {
 	mu   sync.Mutex
@@ -0,0 +1,95 @@
+package freelist
+
+// Linkable is the constraint NewTyped requires of a FreeListTyped's pointer
+// type: PE must be a pointer to E that knows how to read and write its own
+// "next" link. Implementing it usually looks like
+//
+//	type T struct {
+//		...
+//		next *T
+//	}
+//
+//	func (t *T) FreeListNext() *T       { return t.next }
+//	func (t *T) SetFreeListNext(n *T)   { t.next = n }
+//
+// which is exactly the convention FreeList enforces with reflection and an
+// unsafe field offset computed at first use; Linkable lets the compiler
+// check the same convention instead, and lets the "next" field live
+// anywhere in E, including inside an embedded helper.
+//
+// FreeList itself cannot be rewritten in terms of Linkable: PE's methods
+// have to be declared statically on a concrete type, but FreeList's "next"
+// offset is only known once E is, at runtime, for whatever E the caller
+// happens to instantiate it with. FreeList therefore keeps its existing
+// reflect+unsafe init path; FreeListTyped is the way to opt out of it for
+// new code that can implement Linkable.
+type Linkable[E any] interface {
+	*E
+	SetFreeListNext(*E)
+	FreeListNext() *E
+}
+
+// A FreeListTyped is a FreeList whose "next" link is accessed through the
+// Linkable methods on PE rather than through reflection and an unsafe field
+// offset. It provides the same sharded-per-P storage and victim cache as
+// FreeList (the two share a single shardedCore implementation), so it
+// scales the same way under concurrent use, but enforces the pooling
+// convention at compile time instead of with FreeList's run-time check.
+// Unlike FreeList, its zero value is not ready to use: create one with
+// NewTyped.
+//
+// A FreeListTyped must not be copied after first use.
+type FreeListTyped[E any, PE Linkable[E]] struct {
+	noCopy noCopy
+
+	core shardedCore[PE]
+
+	// New optionally specifies a function to generate
+	// a value when Get would otherwise return nil.
+	// It may not be changed concurrently with calls to Get.
+	New func() PE
+
+	// Reset optionally specifies a function to reset
+	// a value when Get would return a cached value.
+	// It may not be changed concurrently with calls to Get.
+	Reset func(PE)
+}
+
+// NewTyped returns a ready to use *FreeListTyped[E, PE], wired to PE's
+// Linkable methods; Put on a FreeListTyped not created by NewTyped panics.
+// The type arguments must be given explicitly, since Go cannot infer them
+// from zero arguments, e.g. NewTyped[T, *T]().
+func NewTyped[E any, PE Linkable[E]]() *FreeListTyped[E, PE] {
+	l := &FreeListTyped[E, PE]{}
+	l.core.getNext = func(x PE) PE { return x.FreeListNext() }
+	l.core.setNext = func(x, v PE) { x.SetFreeListNext(v) }
+	return l
+}
+
+// Get selects a last put back item from the FreeListTyped, removes it, if
+// l.Reset is non-nil calling l.Reset with it, and returns it to the caller.
+//
+// If Get would otherwise return nil and l.New is non-nil, Get returns
+// the result of calling l.New.
+func (l *FreeListTyped[E, PE]) Get() PE {
+	x := l.core.get()
+
+	if x != nil {
+		if l.Reset != nil {
+			l.Reset(x)
+		}
+		return x
+	}
+	if l.New != nil {
+		x = l.New()
+	}
+	return x
+}
+
+// Put adds x to the free list.
+func (l *FreeListTyped[E, PE]) Put(x PE) {
+	if x == nil {
+		return
+	}
+	l.core.put(x)
+}
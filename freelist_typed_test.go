@@ -0,0 +1,59 @@
+package freelist_test
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/weiwenchen2022/freelist"
+)
+
+type typedItem struct {
+	A    string
+	next *typedItem
+}
+
+func (t *typedItem) FreeListNext() *typedItem     { return t.next }
+func (t *typedItem) SetFreeListNext(n *typedItem) { t.next = n }
+
+func TestFreeListTyped(t *testing.T) {
+	l := NewTyped[typedItem, *typedItem]()
+	if l.Get() != nil {
+		t.Fatal("expected empty")
+	}
+
+	l.Put(&typedItem{A: "a"})
+	l.Put(&typedItem{A: "b"})
+
+	if g := l.Get(); g.A != "b" {
+		t.Fatalf(`got %q; want "b"`, g.A)
+	}
+	if g := l.Get(); g.A != "a" {
+		t.Fatalf(`got %q; want "a"`, g.A)
+	}
+}
+
+func TestFreeListTypedConcurrent(t *testing.T) {
+	const goroutines = 32
+	const perGoroutine = 1000
+
+	l := NewTyped[typedItem, *typedItem]()
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				x := l.Get()
+				if x == nil {
+					x = &typedItem{}
+				}
+				l.Put(x)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for l.Get() != nil {
+	}
+}